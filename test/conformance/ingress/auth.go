@@ -0,0 +1,148 @@
+/*
+Copyright 2020 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ingress
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	pkgTest "knative.dev/pkg/test"
+
+	"knative.dev/serving/pkg/apis/networking"
+	"knative.dev/serving/test"
+)
+
+// AuthPolicy configures the behavior of the pod created by CreateAuthService:
+// requests whose HeaderName header equals AllowValue are let through (with
+// InjectHeaders added to the forwarded request); anything else is rejected
+// with DenyStatusCode.
+type AuthPolicy struct {
+	// HeaderName is the request header the auth pod inspects to decide
+	// allow/deny, e.g. "Authorization".
+	HeaderName string
+
+	// AllowValue is the exact header value that results in a 200 from the
+	// auth pod (simulating a successful forwardAuth-style check).
+	AllowValue string
+
+	// DenyStatusCode is returned for any other header value, e.g. 401 or 403.
+	DenyStatusCode int
+
+	// InjectHeaders are added to the response on allow, for an ingress
+	// configured to copy them onto the upstream request (e.g. X-Auth-User).
+	InjectHeaders map[string]string
+}
+
+// CreateAuthService creates a Kubernetes service backed by a pod implementing
+// a forwardAuth-style external authorization check per policy, for use with
+// an ingress configured via an external-auth annotation.
+func CreateAuthService(t *testing.T, clients *test.Clients, policy AuthPolicy) (string, int, context.CancelFunc) {
+	t.Helper()
+	name := test.ObjectNameForTest(t)
+
+	// Avoid zero, but pick a low port number.
+	port := 50 + rand.Intn(50)
+	t.Logf("[%s] Using port %d", name, port)
+
+	// Pick a high port number.
+	containerPort := 8000 + rand.Intn(100)
+	t.Logf("[%s] Using containerPort %d", name, containerPort)
+
+	env := []corev1.EnvVar{{
+		Name:  "PORT",
+		Value: strconv.Itoa(containerPort),
+	}, {
+		Name:  "AUTH_HEADER_NAME",
+		Value: policy.HeaderName,
+	}, {
+		Name:  "AUTH_ALLOW_VALUE",
+		Value: policy.AllowValue,
+	}, {
+		Name:  "AUTH_DENY_STATUS_CODE",
+		Value: strconv.Itoa(policy.DenyStatusCode),
+	}}
+	for k, v := range policy.InjectHeaders {
+		env = append(env, corev1.EnvVar{
+			Name:  "AUTH_INJECT_" + k,
+			Value: v,
+		})
+	}
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: test.ServingNamespace,
+			Labels: map[string]string{
+				"test-pod": name,
+			},
+		},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{
+				Name:  "foo",
+				Image: pkgTest.ImagePath("authserver"),
+				Ports: []corev1.ContainerPort{{
+					Name:          networking.ServicePortNameHTTP1,
+					ContainerPort: int32(containerPort),
+				}},
+				Env: env,
+				ReadinessProbe: &corev1.Probe{
+					Handler: corev1.Handler{
+						HTTPGet: &corev1.HTTPGetAction{
+							Path: "/healthz",
+							Port: intstr.FromInt(containerPort),
+						},
+					},
+				},
+			}},
+		},
+	}
+
+	svc := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: test.ServingNamespace,
+			Labels: map[string]string{
+				"test-pod": name,
+			},
+		},
+		Spec: corev1.ServiceSpec{
+			Type: "ClusterIP",
+			Ports: []corev1.ServicePort{{
+				Name:       networking.ServicePortNameHTTP1,
+				Port:       int32(port),
+				TargetPort: intstr.FromInt(int(containerPort)),
+			}},
+			Selector: map[string]string{
+				"test-pod": name,
+			},
+		},
+	}
+
+	return name, port, createPodAndService(t, clients, pod, svc)
+}
+
+// AuthURL builds the in-cluster URL of the auth service created by
+// CreateAuthService, suitable for an ingress's external-auth annotation.
+func AuthURL(authName string, authPort int) string {
+	return fmt.Sprintf("http://%s.%s.svc.cluster.local:%d", authName, test.ServingNamespace, authPort)
+}
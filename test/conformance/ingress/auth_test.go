@@ -0,0 +1,126 @@
+// +build e2e
+
+/*
+Copyright 2020 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ingress
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"knative.dev/serving/pkg/apis/networking"
+	"knative.dev/serving/pkg/apis/networking/v1alpha1"
+	"knative.dev/serving/test"
+)
+
+// TestExternalAuthAndTracePropagation verifies that an ingress configured
+// with an external-auth annotation (a) propagates 401/403 from the auth
+// service to the client, (b) forwards headers the auth service injects on
+// allow to the upstream, and (c) preserves trace context across the auth
+// subrequest so both the auth pod and the upstream report spans under the
+// same trace.
+func TestExternalAuthAndTracePropagation(t *testing.T) {
+	t.Parallel()
+	clients := test.ServingClients(t)
+
+	collector, collectorAddr, cancelCollector := StartSpanCollector(t)
+	defer cancelCollector()
+
+	authName, authPort, cancelAuth := CreateAuthService(t, clients, AuthPolicy{
+		HeaderName:     "Authorization",
+		AllowValue:     "Bearer good-token",
+		DenyStatusCode: http.StatusForbidden,
+		InjectHeaders: map[string]string{
+			"X-Auth-User": "conformance-test-user",
+		},
+	})
+	defer cancelAuth()
+
+	upstreamName, upstreamPort, cancelUpstream := CreateRuntimeService(t, clients, networking.ServicePortNameHTTP1)
+	defer cancelUpstream()
+
+	host := test.ObjectNameForTest(t) + ".example.com"
+	_, client, cancelIngress := CreateIngressReady(t, clients, v1alpha1.IngressSpec{
+		Rules: []v1alpha1.IngressRule{{
+			Hosts:      []string{host},
+			Visibility: v1alpha1.IngressVisibilityExternalIP,
+			HTTP: &v1alpha1.HTTPIngressRuleValue{
+				Paths: []v1alpha1.HTTPIngressPath{{
+					Splits: []v1alpha1.IngressBackendSplit{{
+						IngressBackend: v1alpha1.IngressBackend{
+							ServiceName:      upstreamName,
+							ServiceNamespace: test.ServingNamespace,
+							ServicePort:      intstr.FromInt(upstreamPort),
+						},
+						Percent: 100,
+					}},
+				}},
+			},
+		}},
+	}, WithAnnotations(map[string]string{
+		"ingress.knative.dev/auth-url":        AuthURL(authName, authPort),
+		"ingress.knative.dev/trace-collector": collectorAddr,
+	}))
+	defer cancelIngress()
+
+	url := "http://" + host
+
+	t.Run("deny without credentials", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, url, nil)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("client.Do() = %v", err)
+		}
+		if resp.StatusCode != http.StatusForbidden {
+			t.Errorf("got status %d, want %d", resp.StatusCode, http.StatusForbidden)
+		}
+	})
+
+	t.Run("allow and propagate trace", func(t *testing.T) {
+		traceID := "4bf92f3577b34da6a3ce929d0e0e4736"
+		req, _ := http.NewRequest(http.MethodGet, url, nil)
+		req.Header.Set("Authorization", "Bearer good-token")
+		req.Header.Set("traceparent", NewTraceParent(traceID, "00f067aa0ba902b7"))
+
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("client.Do() = %v", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("got status %d, want 200", resp.StatusCode)
+		}
+
+		// Parse resp's own body rather than issuing a fresh RuntimeRequest:
+		// a new request wouldn't carry the Authorization header above and
+		// would be denied by the auth service.
+		ri := ParseRuntimeInfo(t, resp)
+		if ri == nil {
+			t.Fatal("ParseRuntimeInfo returned nil")
+		}
+		if got := ri.Request.Headers.Get("X-Auth-User"); got != "conformance-test-user" {
+			t.Errorf("X-Auth-User header = %q, want %q", got, "conformance-test-user")
+		}
+
+		spans := collector.WaitForTraceID(t, traceID, 30*time.Second)
+		if len(spans) < 2 {
+			t.Errorf("Got %d spans for trace %s, want at least 2 (auth + upstream)", len(spans), traceID)
+		}
+	})
+}
@@ -0,0 +1,155 @@
+/*
+Copyright 2020 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ingress
+
+import (
+	"crypto/ecdsa"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"sync"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"knative.dev/serving/test"
+)
+
+// CAPool is a test-scoped, mutable CA trust store. It replaces the old
+// package-level "var rootCAs = x509.NewCertPool()", from which, as a comment
+// in CreateTLSSecret admitted, certs could never be removed: a *x509.CertPool itself
+// has no API for that, so CAPool keeps its own certs and rebuilds the pool
+// from scratch whenever one is added or removed.
+//
+// defaultCAPool is used by CreateTLSSecret and CreateIngressReady when no
+// explicit pool is supplied, preserving their historical (shared,
+// process-wide) behavior. Tests that run in parallel and mint their own TLS
+// secrets should construct their own CAPool with NewCAPool so they don't
+// trust -- or invalidate -- each other's certs.
+type CAPool struct {
+	mu    sync.Mutex
+	certs map[string]*x509.Certificate
+	pool  *x509.CertPool
+
+	rootOnce sync.Once
+	rootKey  *ecdsa.PrivateKey
+	rootCert *x509.Certificate
+}
+
+// defaultCAPool backs CreateTLSSecret and CreateIngressReady when the caller
+// doesn't pass a *CAPool of their own.
+var defaultCAPool = NewCAPool()
+
+// NewCAPool returns an empty CAPool.
+func NewCAPool() *CAPool {
+	return &CAPool{
+		certs: map[string]*x509.Certificate{},
+		pool:  x509.NewCertPool(),
+	}
+}
+
+// Add adds cert to the pool and returns a func that removes it again. Unlike
+// a bare *x509.CertPool, calling the returned func actually un-trusts cert:
+// the underlying pool is rebuilt from whatever certs remain.
+func (p *CAPool) Add(cert *x509.Certificate) func() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	key := certKey(cert)
+	p.certs[key] = cert
+	p.rebuildLocked()
+
+	return func() {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		delete(p.certs, key)
+		p.rebuildLocked()
+	}
+}
+
+func (p *CAPool) rebuildLocked() {
+	pool := x509.NewCertPool()
+	for _, c := range p.certs {
+		pool.AddCert(c)
+	}
+	p.pool = pool
+}
+
+// TLSConfig returns a *tls.Config trusting every cert currently in the pool.
+func (p *CAPool) TLSConfig() *tls.Config {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return &tls.Config{RootCAs: p.pool}
+}
+
+// Rotate issues a new leaf certificate for hosts -- chained off a root CA
+// that persists for the lifetime of the pool, rather than a fresh
+// self-signed cert per call -- and updates the contents of the named Secret
+// in place, so an ingress watching it can pick up the new chain without the
+// Secret itself being recreated.
+func (p *CAPool) Rotate(t *testing.T, clients *test.Clients, secretName string, hosts []string) {
+	t.Helper()
+
+	rootKey, rootCert := p.ensureRoot(t)
+	der, key := generateLeaf(t, rootCert, rootKey, hosts, x509.ExtKeyUsageServerAuth)
+	certPEM, keyPEM := encodeKeyPair(t, der, key)
+
+	secret, err := clients.KubeClient.Kube.CoreV1().Secrets(test.ServingNamespace).Get(secretName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Error getting Secret %s: %v", secretName, err)
+	}
+	// StringData is merged into Data server-side on update, but an object we
+	// just Get'd already has Data populated with the old (base64) contents;
+	// clear it so StringData is what actually takes effect.
+	secret.Data = nil
+	secret.StringData = map[string]string{
+		corev1.TLSCertKey:       certPEM,
+		corev1.TLSPrivateKeyKey: keyPEM,
+	}
+	if _, err := clients.KubeClient.Kube.CoreV1().Secrets(test.ServingNamespace).Update(secret); err != nil {
+		t.Fatalf("Error updating Secret %s: %v", secretName, err)
+	}
+}
+
+// ensureRoot lazily mints the pool's persistent root CA on first use and
+// trusts it, so every Rotate call for this pool chains off the same root.
+func (p *CAPool) ensureRoot(t *testing.T) (*ecdsa.PrivateKey, *x509.Certificate) {
+	t.Helper()
+	p.rootOnce.Do(func() {
+		key, cert, _ := generateCA(t, "Knative Ingress Conformance Testing Root CA")
+		p.rootKey, p.rootCert = key, cert
+		p.Add(cert)
+	})
+	return p.rootKey, p.rootCert
+}
+
+// caPoolFor returns the first non-nil pool in pools, or defaultCAPool if
+// none was supplied -- the "optional *CAPool parameter" pattern used by
+// CreateTLSSecret and CreateIngressReady.
+func caPoolFor(pools ...*CAPool) *CAPool {
+	for _, p := range pools {
+		if p != nil {
+			return p
+		}
+	}
+	return defaultCAPool
+}
+
+func certKey(cert *x509.Certificate) string {
+	return fmt.Sprintf("%x", cert.SerialNumber)
+}
@@ -0,0 +1,111 @@
+// +build e2e
+
+/*
+Copyright 2020 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ingress
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"knative.dev/serving/pkg/apis/networking"
+	"knative.dev/serving/pkg/apis/networking/v1alpha1"
+	"knative.dev/serving/test"
+)
+
+// TestIngressCertificateRotation covers the hot-reload-certificate path:
+// after an ingress is serving over TLS, rotating the contents of its TLS
+// Secret in place (rather than recreating the Ingress) eventually causes the
+// ingress to present the new chain, and a client that only ever trusted the
+// pre-rotation leaf is rejected once that happens.
+func TestIngressCertificateRotation(t *testing.T) {
+	t.Parallel()
+	clients := test.ServingClients(t)
+
+	// A pool scoped to this test, so rotating its root CA can't affect any
+	// other parallel test's trust state.
+	pool := NewCAPool()
+
+	svcName, svcPort, cancelRuntime := CreateRuntimeService(t, clients, networking.ServicePortNameHTTP1)
+	defer cancelRuntime()
+
+	host := test.ObjectNameForTest(t) + ".example.com"
+	secretName, cancelSecret := CreateTLSSecret(t, clients, []string{host}, pool)
+	defer cancelSecret()
+
+	// Snapshot the pool's trust state before rotation: just the pre-rotation
+	// self-signed leaf, since CAPool.TLSConfig returns the *x509.CertPool as
+	// of this call, and later Add calls rebuild a new one rather than
+	// mutating it in place.
+	preRotationConfig := &tls.Config{RootCAs: pool.TLSConfig().RootCAs, ServerName: host}
+
+	ing, client, cancelIngress := CreateIngressReady(t, clients, v1alpha1.IngressSpec{
+		Rules: []v1alpha1.IngressRule{{
+			Hosts:      []string{host},
+			Visibility: v1alpha1.IngressVisibilityExternalIP,
+			HTTP: &v1alpha1.HTTPIngressRuleValue{
+				Paths: []v1alpha1.HTTPIngressPath{{
+					Splits: []v1alpha1.IngressBackendSplit{{
+						IngressBackend: v1alpha1.IngressBackend{
+							ServiceName:      svcName,
+							ServiceNamespace: test.ServingNamespace,
+							ServicePort:      intstr.FromInt(svcPort),
+						},
+						Percent: 100,
+					}},
+				}},
+			},
+		}},
+		TLS: []v1alpha1.IngressTLS{{
+			Hosts:      []string{host},
+			SecretName: secretName,
+		}},
+	}, WithCAPool(pool))
+	defer cancelIngress()
+
+	url := "https://" + host
+	RuntimeRequest(t, client, url)
+
+	pool.Rotate(t, clients, secretName, []string{host})
+
+	dialer := CreateDialContext(t, ing, clients)
+	if err := wait.PollImmediate(test.PollInterval, test.PollTimeout, func() (bool, error) {
+		conn, err := dialer(context.Background(), "tcp", host+":443")
+		if err != nil {
+			return false, nil
+		}
+		tlsConn := tls.Client(conn, preRotationConfig)
+		defer tlsConn.Close()
+		// Once the ingress is serving the rotated chain, a handshake that
+		// only trusts the old leaf must fail.
+		return tlsConn.Handshake() != nil, nil
+	}); err != nil {
+		t.Fatalf("Ingress never stopped serving a chain the pre-rotation leaf trusts: %v", err)
+	}
+
+	postRotationClient := &http.Client{
+		Transport: &http.Transport{
+			DialContext:     dialer,
+			TLSClientConfig: pool.TLSConfig(),
+		},
+	}
+	RuntimeRequest(t, postRotationClient, url)
+}
@@ -0,0 +1,222 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ingress
+
+import (
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	gatewayv1alpha2 "sigs.k8s.io/gateway-api/apis/v1alpha2"
+
+	"knative.dev/serving/pkg/apis/networking/v1alpha1"
+	"knative.dev/serving/test"
+)
+
+// translateIngressSpec maps a Knative IngressSpec onto a Gateway plus the
+// HTTPRoutes needed to realize its rules, translating hosts, paths, header
+// matching, weighted splits, timeouts, and TLS secrets to their Gateway API
+// equivalents.
+//
+// STATUS: this request is only partially implemented. translateIngressSpec
+// is pure, unit-tested translation logic (see gateway_api_test.go); it is
+// NOT wired up to CreateIngress, and there is no `--ingress-backend` flag or
+// client for operators to actually run the conformance matrix against a
+// Gateway API implementation. That requires a test.ServingFlags.IngressBackend
+// flag and a test.Clients.GatewayAPIClient, and neither exists in this
+// checkout, so it can't be added without guessing at the shape of files this
+// package doesn't have visibility into. Landing that plumbing -- and
+// TLSRoute/TCPRoute translation for passthrough rules -- is tracked as
+// follow-up work, not something this commit claims to deliver.
+func translateIngressSpec(name, gatewayClassName string, spec v1alpha1.IngressSpec) (*gatewayv1alpha2.Gateway, []*gatewayv1alpha2.HTTPRoute) {
+	gw := &gatewayv1alpha2.Gateway{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: test.ServingNamespace,
+		},
+		Spec: gatewayv1alpha2.GatewaySpec{
+			GatewayClassName: gatewayv1alpha2.ObjectName(gatewayClassName),
+		},
+	}
+
+	var routes []*gatewayv1alpha2.HTTPRoute
+	for i, rule := range spec.Rules {
+		if len(rule.HTTP.Paths) == 0 {
+			continue
+		}
+		listenerName := gatewayv1alpha2.SectionName(fmt.Sprintf("%s-%d", name, i))
+		if secretName := tlsSecretFor(spec.TLS, rule.Hosts); secretName != "" {
+			gw.Spec.Listeners = append(gw.Spec.Listeners, httpsListener(listenerName, rule.Hosts, secretName))
+		} else {
+			gw.Spec.Listeners = append(gw.Spec.Listeners, httpListener(listenerName, rule.Hosts))
+		}
+		routes = append(routes, httpRouteFor(name, i, gw.Name, rule))
+	}
+
+	return gw, routes
+}
+
+func httpListener(name gatewayv1alpha2.SectionName, hosts []string) gatewayv1alpha2.Listener {
+	return gatewayv1alpha2.Listener{
+		Name:     name,
+		Port:     80,
+		Protocol: gatewayv1alpha2.HTTPProtocolType,
+		Hostname: hostnamePtr(hosts),
+	}
+}
+
+func httpsListener(name gatewayv1alpha2.SectionName, hosts []string, secretName string) gatewayv1alpha2.Listener {
+	return gatewayv1alpha2.Listener{
+		Name:     name,
+		Port:     443,
+		Protocol: gatewayv1alpha2.HTTPSProtocolType,
+		Hostname: hostnamePtr(hosts),
+		TLS: &gatewayv1alpha2.GatewayTLSConfig{
+			CertificateRefs: []gatewayv1alpha2.SecretObjectReference{{
+				Name: gatewayv1alpha2.ObjectName(secretName),
+			}},
+		},
+	}
+}
+
+func hostnamePtr(hosts []string) *gatewayv1alpha2.Hostname {
+	if len(hosts) == 0 {
+		return nil
+	}
+	h := gatewayv1alpha2.Hostname(hosts[0])
+	return &h
+}
+
+// tlsSecretFor returns the secret backing the first IngressTLS entry that
+// applies to any of hosts, or "" if none of spec.TLS covers them.
+func tlsSecretFor(tls []v1alpha1.IngressTLS, hosts []string) string {
+	for _, entry := range tls {
+		for _, h := range hosts {
+			for _, th := range entry.Hosts {
+				if th == h {
+					return entry.SecretName
+				}
+			}
+		}
+	}
+	return ""
+}
+
+func httpRouteFor(name string, ruleIdx int, gatewayName string, rule v1alpha1.IngressRule) *gatewayv1alpha2.HTTPRoute {
+	route := &gatewayv1alpha2.HTTPRoute{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("%s-%d", name, ruleIdx),
+			Namespace: test.ServingNamespace,
+		},
+		Spec: gatewayv1alpha2.HTTPRouteSpec{
+			CommonRouteSpec: gatewayv1alpha2.CommonRouteSpec{
+				ParentRefs: []gatewayv1alpha2.ParentReference{{
+					Name: gatewayv1alpha2.ObjectName(gatewayName),
+				}},
+			},
+			Hostnames: toGatewayHostnames(rule.Hosts),
+		},
+	}
+
+	for _, path := range rule.HTTP.Paths {
+		hr := gatewayv1alpha2.HTTPRouteRule{
+			Matches: matchesFor(path),
+			Timeouts: &gatewayv1alpha2.HTTPRouteTimeouts{
+				Request: durationPtr(path.Timeout),
+			},
+		}
+		for _, split := range path.Splits {
+			hr.BackendRefs = append(hr.BackendRefs, gatewayv1alpha2.HTTPBackendRef{
+				BackendRef: gatewayv1alpha2.BackendRef{
+					BackendObjectReference: gatewayv1alpha2.BackendObjectReference{
+						Name: gatewayv1alpha2.ObjectName(split.ServiceName),
+						Port: portPtr(split.ServicePort.IntVal),
+					},
+					Weight: int32Ptr(int32(split.Percent)),
+				},
+				Filters: headerFiltersFor(split.AppendHeaders),
+			})
+		}
+		route.Spec.Rules = append(route.Spec.Rules, hr)
+	}
+
+	return route
+}
+
+func matchesFor(path v1alpha1.HTTPIngressPath) []gatewayv1alpha2.HTTPRouteMatch {
+	pathMatchType := gatewayv1alpha2.PathMatchPathPrefix
+	pathValue := "/"
+	if path.Path != "" {
+		pathValue = path.Path
+	}
+
+	match := gatewayv1alpha2.HTTPRouteMatch{
+		Path: &gatewayv1alpha2.HTTPPathMatch{
+			Type:  &pathMatchType,
+			Value: &pathValue,
+		},
+	}
+	for k, v := range path.Headers {
+		exact := gatewayv1alpha2.HeaderMatchExact
+		match.Headers = append(match.Headers, gatewayv1alpha2.HTTPHeaderMatch{
+			Type:  &exact,
+			Name:  gatewayv1alpha2.HTTPHeaderName(k),
+			Value: v.Exact,
+		})
+	}
+	return []gatewayv1alpha2.HTTPRouteMatch{match}
+}
+
+func headerFiltersFor(headers map[string]string) []gatewayv1alpha2.HTTPRouteFilter {
+	if len(headers) == 0 {
+		return nil
+	}
+	set := make([]gatewayv1alpha2.HTTPHeader, 0, len(headers))
+	for k, v := range headers {
+		set = append(set, gatewayv1alpha2.HTTPHeader{Name: gatewayv1alpha2.HTTPHeaderName(k), Value: v})
+	}
+	return []gatewayv1alpha2.HTTPRouteFilter{{
+		Type: gatewayv1alpha2.HTTPRouteFilterRequestHeaderModifier,
+		RequestHeaderModifier: &gatewayv1alpha2.HTTPHeaderFilter{
+			Set: set,
+		},
+	}}
+}
+
+func toGatewayHostnames(hosts []string) []gatewayv1alpha2.Hostname {
+	out := make([]gatewayv1alpha2.Hostname, 0, len(hosts))
+	for _, h := range hosts {
+		out = append(out, gatewayv1alpha2.Hostname(h))
+	}
+	return out
+}
+
+func durationPtr(d metav1.Duration) *gatewayv1alpha2.Duration {
+	if d.Duration == 0 {
+		return nil
+	}
+	gd := gatewayv1alpha2.Duration(d.Duration.String())
+	return &gd
+}
+
+func portPtr(p int32) *gatewayv1alpha2.PortNumber {
+	pn := gatewayv1alpha2.PortNumber(p)
+	return &pn
+}
+
+func int32Ptr(i int32) *int32 {
+	return &i
+}
@@ -0,0 +1,97 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ingress
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/util/intstr"
+	gatewayv1alpha2 "sigs.k8s.io/gateway-api/apis/v1alpha2"
+
+	"knative.dev/serving/pkg/apis/networking/v1alpha1"
+)
+
+// TestTranslateIngressSpec covers translateIngressSpec's two listener
+// shapes (plain HTTP, and HTTPS once a TLS secret covers the rule's hosts)
+// and its weighted-split-to-BackendRef mapping. It's the only conformance
+// coverage this translation logic has -- see the STATUS note on
+// translateIngressSpec for what's still missing before it can be exercised
+// end-to-end against a live Gateway API cluster.
+func TestTranslateIngressSpec(t *testing.T) {
+	spec := v1alpha1.IngressSpec{
+		Rules: []v1alpha1.IngressRule{{
+			Hosts: []string{"foo.example.com"},
+			HTTP: &v1alpha1.HTTPIngressRuleValue{
+				Paths: []v1alpha1.HTTPIngressPath{{
+					Splits: []v1alpha1.IngressBackendSplit{{
+						IngressBackend: v1alpha1.IngressBackend{
+							ServiceName: "foo",
+							ServicePort: intstr.FromInt(80),
+						},
+						Percent: 100,
+					}},
+				}},
+			},
+		}, {
+			Hosts: []string{"bar.example.com"},
+			HTTP: &v1alpha1.HTTPIngressRuleValue{
+				Paths: []v1alpha1.HTTPIngressPath{{
+					Splits: []v1alpha1.IngressBackendSplit{{
+						IngressBackend: v1alpha1.IngressBackend{
+							ServiceName: "bar",
+							ServicePort: intstr.FromInt(80),
+						},
+						Percent: 100,
+					}},
+				}},
+			},
+		}},
+		TLS: []v1alpha1.IngressTLS{{
+			Hosts:      []string{"bar.example.com"},
+			SecretName: "bar-certs",
+		}},
+	}
+
+	gw, routes := translateIngressSpec("test-ing", "test-gateway-class", spec)
+
+	if got := string(gw.Spec.GatewayClassName); got != "test-gateway-class" {
+		t.Errorf("GatewayClassName = %q, want %q", got, "test-gateway-class")
+	}
+	if len(gw.Spec.Listeners) != 2 {
+		t.Fatalf("len(Listeners) = %d, want 2", len(gw.Spec.Listeners))
+	}
+
+	httpListener, httpsListener := gw.Spec.Listeners[0], gw.Spec.Listeners[1]
+	if httpListener.Protocol != gatewayv1alpha2.HTTPProtocolType || httpListener.Port != 80 {
+		t.Errorf("Listeners[0] = %+v, want plain HTTP on port 80", httpListener)
+	}
+	if httpsListener.Protocol != gatewayv1alpha2.HTTPSProtocolType || httpsListener.Port != 443 {
+		t.Errorf("Listeners[1] = %+v, want HTTPS on port 443", httpsListener)
+	}
+	if httpsListener.TLS == nil || len(httpsListener.TLS.CertificateRefs) != 1 ||
+		string(httpsListener.TLS.CertificateRefs[0].Name) != "bar-certs" {
+		t.Errorf("Listeners[1].TLS = %+v, want a CertificateRef to %q", httpsListener.TLS, "bar-certs")
+	}
+
+	if len(routes) != 2 {
+		t.Fatalf("len(routes) = %d, want 2", len(routes))
+	}
+	backends := routes[0].Spec.Rules[0].BackendRefs
+	if len(backends) != 1 || string(backends[0].Name) != "foo" || *backends[0].Weight != 100 {
+		t.Errorf("routes[0] BackendRefs = %+v, want a single 100%%-weighted ref to foo", backends)
+	}
+}
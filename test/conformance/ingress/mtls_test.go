@@ -0,0 +1,101 @@
+// +build e2e
+
+/*
+Copyright 2020 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ingress
+
+import (
+	"crypto/tls"
+	"net/http"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"knative.dev/serving/pkg/apis/networking"
+	"knative.dev/serving/pkg/apis/networking/v1alpha1"
+	"knative.dev/serving/test"
+)
+
+// TestIngressMTLS verifies that an ingress configured to require client
+// certificates (via the ingress-class's mTLS annotation) rejects unauthenticated
+// and wrongly-signed clients, while accepting one presenting a cert signed by
+// the expected CA.
+func TestIngressMTLS(t *testing.T) {
+	t.Parallel()
+	clients := test.ServingClients(t)
+
+	name, port, cancel := CreateRuntimeService(t, clients, networking.ServicePortNameHTTP1)
+	defer cancel()
+
+	hosts := []string{name + ".example.com"}
+	secrets, clientCert, cancelSecrets := CreateMTLSSecrets(t, clients, hosts)
+	defer cancelSecrets()
+
+	_, otherClientCert, cancelOther := CreateMTLSSecrets(t, clients, hosts)
+	defer cancelOther()
+
+	ing, _, cancelIngress := CreateIngressReadyDialContext(t, clients, v1alpha1.IngressSpec{
+		Rules: []v1alpha1.IngressRule{{
+			Hosts:      hosts,
+			Visibility: v1alpha1.IngressVisibilityExternalIP,
+			HTTP: &v1alpha1.HTTPIngressRuleValue{
+				Paths: []v1alpha1.HTTPIngressPath{{
+					Splits: []v1alpha1.IngressBackendSplit{{
+						IngressBackend: v1alpha1.IngressBackend{
+							ServiceName:      name,
+							ServiceNamespace: test.ServingNamespace,
+							ServicePort:      intstr.FromInt(port),
+						},
+						Percent: 100,
+					}},
+				}},
+			},
+		}},
+		TLS: []v1alpha1.IngressTLS{{
+			Hosts:      hosts,
+			SecretName: secrets.Server,
+		}},
+	}, WithAnnotations(map[string]string{
+		MTLSCABundleAnnotationKey: secrets.CABundle,
+	}))
+	defer cancelIngress()
+
+	url := "https://" + hosts[0]
+
+	t.Run("no client cert", func(t *testing.T) {
+		client := &http.Client{
+			Transport: &http.Transport{
+				DialContext:     CreateDialContext(t, ing, clients),
+				TLSClientConfig: defaultCAPool.TLSConfig(),
+			},
+		}
+		if _, err := client.Get(url); err == nil {
+			t.Error("Expected TLS handshake to fail without a client cert, got nil error")
+		}
+	})
+
+	t.Run("wrong CA client cert", func(t *testing.T) {
+		client := MTLSClient(t, clients, ing, otherClientCert, nil)
+		if _, err := client.Get(url); err == nil {
+			t.Error("Expected TLS handshake to fail with a client cert signed by the wrong CA, got nil error")
+		}
+	})
+
+	t.Run("proper client cert", func(t *testing.T) {
+		client := MTLSClient(t, clients, ing, clientCert, nil)
+		RuntimeRequest(t, client, url)
+	})
+}
@@ -0,0 +1,159 @@
+/*
+Copyright 2020 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ingress
+
+import (
+	"math"
+	"net/http"
+	"sort"
+	"sync"
+	"testing"
+
+	"knative.dev/serving/test/types"
+)
+
+// chiSquareCriticalValue99 maps degrees of freedom (len(expected)-1) to the
+// 99% critical value of the chi-square distribution, for the range of split
+// sizes the conformance suite exercises. Beyond 10 degrees of freedom we fall
+// back to the widely-used Wilson-Hilferty normal approximation.
+var chiSquareCriticalValue99 = map[int]float64{
+	1:  6.635,
+	2:  9.210,
+	3:  11.345,
+	4:  13.277,
+	5:  15.086,
+	6:  16.812,
+	7:  18.475,
+	8:  20.090,
+	9:  21.666,
+	10: 23.209,
+}
+
+// AssertSplitDistribution fires n requests against url in parallel, buckets
+// each response by label(ri), and fails the test unless the observed
+// distribution of buckets is consistent with expected (fractions that must
+// sum to ~1) under a chi-square goodness-of-fit test at the 99% confidence
+// level. It's intended for validating the Splits field of an IngressSpec,
+// where simple "is the count roughly proportional" assertions are prone to
+// both false positives (lucky runs) and false negatives (flaky thresholds).
+func AssertSplitDistribution(t *testing.T, client *http.Client, url string, expected map[string]float64, n int, tolerance float64, label func(*types.RuntimeInfo) string) {
+	t.Helper()
+
+	var (
+		mu       sync.Mutex
+		observed = make(map[string]int, len(expected))
+		wg       sync.WaitGroup
+	)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ri := RuntimeRequest(t, client, url)
+			if ri == nil {
+				return
+			}
+			key := label(ri)
+			mu.Lock()
+			observed[key]++
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	total := 0
+	for _, c := range observed {
+		total += c
+	}
+	if total == 0 {
+		t.Fatalf("AssertSplitDistribution: got no successful responses out of %d requests", n)
+	}
+
+	logSplitBuckets(t, expected, observed, total)
+	assertChiSquareFit(t, expected, observed, total)
+
+	// Also sanity check against the simpler per-bucket tolerance, which
+	// catches degenerate cases (e.g. expected fractions summing to <1) that
+	// a chi-square pass alone wouldn't flag clearly in the failure message.
+	for key, frac := range expected {
+		want := frac * float64(total)
+		got := float64(observed[key])
+		if diff := got - want; diff > tolerance*want || diff < -tolerance*want {
+			t.Logf("Bucket %q deviates from expected by more than tolerance (got %.0f, want %.0f +/- %.0f%%)", key, got, want, tolerance*100)
+		}
+	}
+}
+
+// chiSquareRecorder is the subset of *testing.T that assertChiSquareFit
+// needs, factored out so split_test.go can exercise it against known
+// observed/expected counts with a fake recorder instead of firing real
+// requests through AssertSplitDistribution.
+type chiSquareRecorder interface {
+	Helper()
+	Errorf(format string, args ...interface{})
+}
+
+// assertChiSquareFit is AssertSplitDistribution's statistical core: given the
+// already-tallied observed/expected counts, it fails t unless they're
+// consistent under a chi-square goodness-of-fit test at the 99% confidence
+// level. expected must have at least 2 buckets: a single-bucket distribution
+// has zero degrees of freedom, for which the Wilson-Hilferty approximation
+// below is undefined (it divides by df), so that case is rejected outright
+// rather than silently always passing.
+func assertChiSquareFit(t chiSquareRecorder, expected map[string]float64, observed map[string]int, total int) {
+	t.Helper()
+
+	df := len(expected) - 1
+	if df < 1 {
+		t.Errorf("AssertSplitDistribution: need at least 2 expected buckets to run a chi-square test, got %d", len(expected))
+		return
+	}
+
+	chiSquare := 0.0
+	for key, frac := range expected {
+		want := frac * float64(total)
+		got := float64(observed[key])
+		chiSquare += (got - want) * (got - want) / want
+	}
+
+	critical, ok := chiSquareCriticalValue99[df]
+	if !ok {
+		// Wilson-Hilferty approximation of the 99% quantile for df > 10.
+		z := 2.326 // 99th percentile of the standard normal distribution.
+		x := float64(df)
+		w := 1 - 2/(9*x) + z*math.Sqrt(2/(9*x))
+		critical = x * w * w * w
+	}
+
+	if chiSquare > critical {
+		t.Errorf("Observed traffic split does not match expected within tolerance: chi-square=%.3f, critical(99%%, df=%d)=%.3f", chiSquare, df, critical)
+	}
+}
+
+func logSplitBuckets(t *testing.T, expected map[string]float64, observed map[string]int, total int) {
+	t.Helper()
+
+	keys := make([]string, 0, len(expected))
+	for k := range expected {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		want := expected[k] * float64(total)
+		t.Logf("split bucket %q: observed=%d expected=%.1f (%.1f%%)", k, observed[k], want, expected[k]*100)
+	}
+}
@@ -0,0 +1,93 @@
+/*
+Copyright 2020 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ingress
+
+import "testing"
+
+// fakeChiSquareRecorder is a minimal chiSquareRecorder that records whether
+// Errorf was called, so assertChiSquareFit can be driven with known
+// observed/expected counts without firing real requests through
+// AssertSplitDistribution.
+type fakeChiSquareRecorder struct {
+	failed bool
+}
+
+func (f *fakeChiSquareRecorder) Helper() {}
+
+func (f *fakeChiSquareRecorder) Errorf(format string, args ...interface{}) {
+	f.failed = true
+}
+
+// TestAssertChiSquareFit covers the known-good split shapes AssertSplitDistribution
+// is used for, the Wilson-Hilferty approximation path taken once df > 10, and
+// the df < 1 case (a single expected bucket) that used to divide by zero and
+// silently pass regardless of the observed distribution.
+func TestAssertChiSquareFit(t *testing.T) {
+	elevenWayExpected := make(map[string]float64, 11)
+	elevenWayObserved := make(map[string]int, 11)
+	for i := 0; i < 11; i++ {
+		key := string(rune('a' + i))
+		elevenWayExpected[key] = 1.0 / 11
+		elevenWayObserved[key] = 9
+	}
+	elevenWayObserved["a"] = 10 // total = 100
+
+	tests := []struct {
+		name     string
+		expected map[string]float64
+		observed map[string]int
+		total    int
+		wantFail bool
+	}{{
+		name:     "even two-way split matches",
+		expected: map[string]float64{"a": 0.5, "b": 0.5},
+		observed: map[string]int{"a": 50, "b": 50},
+		total:    100,
+	}, {
+		name:     "heavily skewed two-way split fails",
+		expected: map[string]float64{"a": 0.5, "b": 0.5},
+		observed: map[string]int{"a": 95, "b": 5},
+		total:    100,
+		wantFail: true,
+	}, {
+		name:     "three-way split matches",
+		expected: map[string]float64{"a": 1.0 / 3, "b": 1.0 / 3, "c": 1.0 / 3},
+		observed: map[string]int{"a": 34, "b": 33, "c": 33},
+		total:    100,
+	}, {
+		name:     "eleven-way split exercises the Wilson-Hilferty approximation",
+		expected: elevenWayExpected,
+		observed: elevenWayObserved,
+		total:    100,
+	}, {
+		name:     "single expected bucket is rejected instead of dividing by zero",
+		expected: map[string]float64{"a": 1.0},
+		observed: map[string]int{"a": 100},
+		total:    100,
+		wantFail: true,
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := &fakeChiSquareRecorder{}
+			assertChiSquareFit(rec, tt.expected, tt.observed, tt.total)
+			if rec.failed != tt.wantFail {
+				t.Errorf("failed = %v, want %v", rec.failed, tt.wantFail)
+			}
+		})
+	}
+}
@@ -0,0 +1,228 @@
+/*
+Copyright 2020 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ingress
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	cryptorand "crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	pkgTest "knative.dev/pkg/test"
+
+	"knative.dev/serving/pkg/apis/networking"
+	"knative.dev/serving/pkg/apis/networking/v1alpha1"
+	"knative.dev/serving/test"
+)
+
+// CreateTLSPassthroughService creates a Kubernetes service backed by a pod
+// that terminates TLS itself, using a secret minted with
+// createPassthroughTLSSecret (whose CA is not added to the shared CAPool,
+// so the test client must trust it explicitly). It's for tests of an
+// ingress rule that passes TLS through to the pod without re-terminating at
+// the ingress.
+func CreateTLSPassthroughService(t *testing.T, clients *test.Clients, hosts []string) (string, int, *x509.Certificate, context.CancelFunc) {
+	t.Helper()
+	name := test.ObjectNameForTest(t)
+
+	// Avoid zero, but pick a low port number.
+	port := 50 + rand.Intn(50)
+	t.Logf("[%s] Using port %d", name, port)
+
+	// Pick a high port number.
+	containerPort := 8000 + rand.Intn(100)
+	t.Logf("[%s] Using containerPort %d", name, containerPort)
+
+	secretName, caCert, cancelSecret := createPassthroughTLSSecret(t, clients, hosts)
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: test.ServingNamespace,
+			Labels: map[string]string{
+				"test-pod": name,
+			},
+		},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{
+				Name:  "foo",
+				Image: pkgTest.ImagePath("tlsserver"),
+				Ports: []corev1.ContainerPort{{
+					Name:          networking.ServicePortNameHTTP1,
+					ContainerPort: int32(containerPort),
+				}},
+				// This is needed by the tlsserver image we are using.
+				Env: []corev1.EnvVar{{
+					Name:  "PORT",
+					Value: strconv.Itoa(containerPort),
+				}},
+				VolumeMounts: []corev1.VolumeMount{{
+					Name:      "tls",
+					MountPath: "/var/run/tls",
+					ReadOnly:  true,
+				}},
+				ReadinessProbe: &corev1.Probe{
+					Handler: corev1.Handler{
+						TCPSocket: &corev1.TCPSocketAction{
+							Port: intstr.FromInt(containerPort),
+						},
+					},
+				},
+			}},
+			Volumes: []corev1.Volume{{
+				Name: "tls",
+				VolumeSource: corev1.VolumeSource{
+					Secret: &corev1.SecretVolumeSource{
+						SecretName: secretName,
+					},
+				},
+			}},
+		},
+	}
+
+	svc := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: test.ServingNamespace,
+			Labels: map[string]string{
+				"test-pod": name,
+			},
+		},
+		Spec: corev1.ServiceSpec{
+			Type: "ClusterIP",
+			Ports: []corev1.ServicePort{{
+				Name:       networking.ServicePortNameHTTP1,
+				Port:       int32(port),
+				TargetPort: intstr.FromInt(int(containerPort)),
+			}},
+			Selector: map[string]string{
+				"test-pod": name,
+			},
+		},
+	}
+
+	cancelService := createPodAndService(t, clients, pod, svc)
+	return name, port, caCert, func() {
+		cancelService()
+		cancelSecret()
+	}
+}
+
+// createPassthroughTLSSecret mints a server certificate for hosts like
+// CreateTLSSecret, but deliberately does not add it to the package-level
+// CAPool: passthrough tests need the ingress to forward the raw TLS
+// bytes without trusting (or terminating) this cert, so the test client
+// trusts it explicitly via the returned *x509.Certificate instead.
+func createPassthroughTLSSecret(t *testing.T, clients *test.Clients, hosts []string) (string, *x509.Certificate, context.CancelFunc) {
+	t.Helper()
+
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), cryptorand.Reader)
+	if err != nil {
+		t.Fatalf("ecdsa.GenerateKey() = %v", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: newSerialNumber(t),
+		Subject: pkix.Name{
+			Organization: []string{"Knative Ingress Conformance Testing"},
+		},
+		NotBefore: time.Now(),
+		NotAfter:  time.Now().Add(5 * time.Minute),
+
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+
+		DNSNames: hosts,
+	}
+
+	derBytes, err := x509.CreateCertificate(cryptorand.Reader, template, template, &priv.PublicKey, priv)
+	if err != nil {
+		t.Fatalf("x509.CreateCertificate() = %v", err)
+	}
+	cert, err := x509.ParseCertificate(derBytes)
+	if err != nil {
+		t.Fatalf("x509.ParseCertificate() = %v", err)
+	}
+	// Deliberately not added to any CAPool; see the doc comment above.
+
+	certPEM, keyPEM := encodeKeyPair(t, derBytes, priv)
+
+	name := test.ObjectNameForTest(t)
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: test.ServingNamespace,
+			Labels: map[string]string{
+				"test-secret": name,
+			},
+		},
+		Type: corev1.SecretTypeTLS,
+		StringData: map[string]string{
+			corev1.TLSCertKey:       certPEM,
+			corev1.TLSPrivateKeyKey: keyPEM,
+		},
+	}
+	name, cancel := createSecret(t, clients, secret)
+	return name, cert, cancel
+}
+
+// TLSPassthroughAnnotationKey marks an Ingress as routing its rules purely
+// on SNI and forwarding the client's TLS bytes to the backend unmodified,
+// for ingress-classes that support passthrough. Without it, a rule that
+// otherwise looks like ordinary HTTP (as CreateIngressReadyPassthroughDialContext's
+// callers build) would be indistinguishable from one meant to be terminated
+// at the ingress.
+const TLSPassthroughAnnotationKey = "networking.knative.dev/tls-passthrough"
+
+// CreateIngressReadyPassthroughDialContext is the TLS-passthrough sibling of
+// CreateIngressReadyDialContext: it sets TLSPassthroughAnnotationKey on the
+// Ingress (in addition to any opts the caller passes) so the rule is
+// actually realized as passthrough rather than plain HTTP, and the returned
+// *tls.Config trusts caCert explicitly (rather than the shared CAPool), for
+// ingresses that forward TLS bytes to the pod without re-terminating them.
+func CreateIngressReadyPassthroughDialContext(t *testing.T, clients *test.Clients, spec v1alpha1.IngressSpec, caCert *x509.Certificate, opts ...IngressOption) (*v1alpha1.Ingress, func(context.Context, string, string) (net.Conn, error), *tls.Config, *http.Client, context.CancelFunc) {
+	t.Helper()
+
+	opts = append(opts, WithAnnotations(map[string]string{TLSPassthroughAnnotationKey: "true"}))
+	ing, dialer, cancel := CreateIngressReadyDialContext(t, clients, spec, opts...)
+
+	pool := x509.NewCertPool()
+	pool.AddCert(caCert)
+	tlsConfig := &tls.Config{RootCAs: pool}
+
+	client := &http.Client{
+		Transport: &http.Transport{
+			DialContext:     dialer,
+			TLSClientConfig: tlsConfig,
+		},
+	}
+	return ing, dialer, tlsConfig, client, cancel
+}
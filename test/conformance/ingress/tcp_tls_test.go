@@ -0,0 +1,93 @@
+// +build e2e
+
+/*
+Copyright 2020 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ingress
+
+import (
+	"context"
+	"crypto/tls"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"knative.dev/serving/pkg/apis/networking/v1alpha1"
+	"knative.dev/serving/test"
+)
+
+// TestTLSPassthrough verifies that an ingress rule configured for TLS
+// passthrough routes purely on SNI and forwards the client's TLS bytes
+// unmodified, so the certificate the client sees is the backing pod's own
+// certificate rather than one terminated at the ingress.
+func TestTLSPassthrough(t *testing.T) {
+	t.Parallel()
+	clients := test.ServingClients(t)
+
+	host := test.ObjectNameForTest(t) + ".example.com"
+	name, port, caCert, cancelService := CreateTLSPassthroughService(t, clients, []string{host})
+	defer cancelService()
+
+	ing, dialer, tlsConfig, client, cancelIngress := CreateIngressReadyPassthroughDialContext(t, clients, v1alpha1.IngressSpec{
+		Rules: []v1alpha1.IngressRule{{
+			Hosts:      []string{host},
+			Visibility: v1alpha1.IngressVisibilityExternalIP,
+			HTTP: &v1alpha1.HTTPIngressRuleValue{
+				Paths: []v1alpha1.HTTPIngressPath{{
+					Splits: []v1alpha1.IngressBackendSplit{{
+						IngressBackend: v1alpha1.IngressBackend{
+							ServiceName:      name,
+							ServiceNamespace: test.ServingNamespace,
+							ServicePort:      intstr.FromInt(port),
+						},
+						Percent: 100,
+					}},
+				}},
+			},
+		}},
+	}, caCert)
+	defer cancelIngress()
+
+	if got := ing.Annotations[TLSPassthroughAnnotationKey]; got != "true" {
+		t.Fatalf("Ingress annotation %s = %q, want %q", TLSPassthroughAnnotationKey, got, "true")
+	}
+
+	// Dial through the ingress, using SNI to route to the right backend, and
+	// confirm the presented leaf certificate is the pod's own -- proof the
+	// ingress forwarded the TLS bytes rather than re-terminating them.
+	conn, err := dialer(context.Background(), "tcp", host+":443")
+	if err != nil {
+		t.Fatalf("Error dialing through ingress: %v", err)
+	}
+	tlsConn := tls.Client(conn, &tls.Config{RootCAs: tlsConfig.RootCAs, ServerName: host})
+	defer tlsConn.Close()
+	if err := tlsConn.Handshake(); err != nil {
+		t.Fatalf("TLS handshake failed: %v", err)
+	}
+
+	state := tlsConn.ConnectionState()
+	if len(state.PeerCertificates) == 0 {
+		t.Fatal("No peer certificates presented")
+	}
+	if !state.PeerCertificates[0].Equal(caCert) {
+		t.Error("Presented certificate does not match the backing pod's certificate; ingress may have re-terminated TLS instead of passing it through")
+	}
+
+	// The http.Client returned alongside the dialer exercises the same path
+	// end-to-end for the echoed runtime payload.
+	if _, err := client.Get("https://" + host); err != nil {
+		t.Errorf("client.Get() = %v", err)
+	}
+}
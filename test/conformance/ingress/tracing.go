@@ -0,0 +1,138 @@
+/*
+Copyright 2020 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ingress
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Span is the minimal slice of an OpenTelemetry span the conformance suite
+// needs to assert that trace context survived a hop through an ingress (and
+// whatever external-auth subrequest it issued): enough to tell which spans
+// share a trace and how they're parented, not a general-purpose OTLP model.
+type Span struct {
+	TraceID      string `json:"traceId"`
+	SpanID       string `json:"spanId"`
+	ParentSpanID string `json:"parentSpanId"`
+	Name         string `json:"name"`
+}
+
+// SpanCollector is a tiny in-test span sink: it listens on the pod network
+// for JSON-encoded Span payloads (one Span per POST to "/"), which the
+// runtime image emits instead of talking to a real OpenTelemetry collector.
+// It exists purely so ingress conformance tests can assert that trace
+// context propagates end to end without standing up real tracing
+// infrastructure.
+type SpanCollector struct {
+	mu    sync.Mutex
+	spans []Span
+	srv   *http.Server
+}
+
+// StartSpanCollector starts a SpanCollector listening on all interfaces and
+// returns it along with its listener's address (host:port) and a cancel func
+// that shuts the listener down. The caller passes the address on to whatever
+// should emit spans; TestExternalAuthAndTracePropagation puts it in an
+// ingress annotation.
+func StartSpanCollector(t *testing.T) (*SpanCollector, string, context.CancelFunc) {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", ":0")
+	if err != nil {
+		t.Fatalf("net.Listen() = %v", err)
+	}
+
+	c := &SpanCollector{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", c.handle)
+	c.srv = &http.Server{Handler: mux}
+
+	go func() {
+		if err := c.srv.Serve(ln); err != nil && err != http.ErrServerClosed {
+			t.Logf("SpanCollector: Serve() = %v", err)
+		}
+	}()
+
+	return c, ln.Addr().String(), func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := c.srv.Shutdown(ctx); err != nil {
+			t.Errorf("SpanCollector: Shutdown() = %v", err)
+		}
+	}
+}
+
+func (c *SpanCollector) handle(w http.ResponseWriter, r *http.Request) {
+	var span Span
+	if err := json.NewDecoder(r.Body).Decode(&span); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	c.mu.Lock()
+	c.spans = append(c.spans, span)
+	c.mu.Unlock()
+	w.WriteHeader(http.StatusOK)
+}
+
+// Spans returns the spans received so far.
+func (c *SpanCollector) Spans() []Span {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	out := make([]Span, len(c.spans))
+	copy(out, c.spans)
+	return out
+}
+
+// WaitForTraceID polls until at least one span with the given trace ID has
+// been received (or timeout elapses), and returns whatever spans share that
+// trace ID. This is how tests confirm that a B3 or W3C traceparent header set
+// by the client survived an ingress + external-auth hop intact, rather than
+// the upstream starting a disconnected trace of its own.
+func (c *SpanCollector) WaitForTraceID(t *testing.T, traceID string, timeout time.Duration) []Span {
+	t.Helper()
+
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		var matched []Span
+		for _, s := range c.Spans() {
+			if s.TraceID == traceID {
+				matched = append(matched, s)
+			}
+		}
+		if len(matched) > 0 {
+			return matched
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	t.Fatalf("Timed out waiting for a span with trace ID %s", traceID)
+	return nil
+}
+
+// NewTraceParent builds a W3C traceparent header value
+// (https://www.w3.org/TR/trace-context/#traceparent-header) from the given
+// trace and span IDs, with the sampled flag set. The caller keeps traceID to
+// match it against spans received by a SpanCollector.
+func NewTraceParent(traceID, spanID string) string {
+	return fmt.Sprintf("00-%s-%s-01", traceID, spanID)
+}
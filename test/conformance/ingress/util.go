@@ -52,8 +52,6 @@ import (
 	v1a1test "knative.dev/serving/test/v1alpha1"
 )
 
-var rootCAs = x509.NewCertPool()
-
 // CreateRuntimeService creates a Kubernetes service that will respond to the protocol
 // specified with the given portName.  It returns the service name, the port on
 // which the service is listening, and a "cancel" function to clean up the
@@ -475,19 +473,67 @@ func createPodAndService(t *testing.T, clients *test.Clients, pod *corev1.Pod, s
 	}
 }
 
+// ingressOptions collects the knobs IngressOption can set; see
+// resolveIngressOptions.
+type ingressOptions struct {
+	annotations map[string]string
+	pool        *CAPool
+}
+
+// IngressOption customizes Ingress creation across CreateIngress and its
+// CreateIngressReadyDialContext/CreateIngressReady derivatives, so
+// conformance cases that need more than plain routing (an mTLS trust
+// bundle, an external-auth annotation, a TLS-passthrough signal, a non-default
+// *CAPool, …) can compose exactly the knobs they need instead of each
+// reaching for its own parallel entrypoint.
+type IngressOption func(*ingressOptions)
+
+// WithAnnotations merges extra onto the Ingress' annotations, in addition to
+// the ingress-class annotation CreateIngress always sets. Later options win
+// on key collision.
+func WithAnnotations(extra map[string]string) IngressOption {
+	return func(o *ingressOptions) {
+		for k, v := range extra {
+			o.annotations[k] = v
+		}
+	}
+}
+
+// WithCAPool routes the TLS trust pool CreateIngressReady builds its
+// *http.Client from through pool instead of defaultCAPool -- the same pool a
+// caller passed to CreateTLSSecret/CreateMTLSSecrets for the hosts this
+// Ingress serves.
+func WithCAPool(pool *CAPool) IngressOption {
+	return func(o *ingressOptions) { o.pool = pool }
+}
+
+func resolveIngressOptions(opts []IngressOption) *ingressOptions {
+	o := &ingressOptions{annotations: map[string]string{}}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
 // CreateIngress creates a Knative Ingress resource
-func CreateIngress(t *testing.T, clients *test.Clients, spec v1alpha1.IngressSpec) (*v1alpha1.Ingress, context.CancelFunc) {
+func CreateIngress(t *testing.T, clients *test.Clients, spec v1alpha1.IngressSpec, opts ...IngressOption) (*v1alpha1.Ingress, context.CancelFunc) {
 	t.Helper()
 	name := test.ObjectNameForTest(t)
+	o := resolveIngressOptions(opts)
+
+	annotations := map[string]string{
+		networking.IngressClassAnnotationKey: test.ServingFlags.IngressClass,
+	}
+	for k, v := range o.annotations {
+		annotations[k] = v
+	}
 
 	// Create a simple Ingress over the Service.
 	ing := &v1alpha1.Ingress{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      name,
-			Namespace: test.ServingNamespace,
-			Annotations: map[string]string{
-				networking.IngressClassAnnotationKey: test.ServingFlags.IngressClass,
-			},
+			Name:        name,
+			Namespace:   test.ServingNamespace,
+			Annotations: annotations,
 		},
 		Spec: spec,
 	}
@@ -505,9 +551,9 @@ func CreateIngress(t *testing.T, clients *test.Clients, spec v1alpha1.IngressSpe
 	}
 }
 
-func CreateIngressReadyDialContext(t *testing.T, clients *test.Clients, spec v1alpha1.IngressSpec) (*v1alpha1.Ingress, func(context.Context, string, string) (net.Conn, error), context.CancelFunc) {
+func CreateIngressReadyDialContext(t *testing.T, clients *test.Clients, spec v1alpha1.IngressSpec, opts ...IngressOption) (*v1alpha1.Ingress, func(context.Context, string, string) (net.Conn, error), context.CancelFunc) {
 	t.Helper()
-	ing, cancel := CreateIngress(t, clients, spec)
+	ing, cancel := CreateIngress(t, clients, spec, opts...)
 
 	if err := v1a1test.WaitForIngressState(clients.NetworkingClient, ing.Name, v1a1test.IsIngressReady, t.Name()); err != nil {
 		cancel()
@@ -523,19 +569,23 @@ func CreateIngressReadyDialContext(t *testing.T, clients *test.Clients, spec v1a
 	return ing, CreateDialContext(t, ing, clients), cancel
 }
 
-func CreateIngressReady(t *testing.T, clients *test.Clients, spec v1alpha1.IngressSpec) (*v1alpha1.Ingress, *http.Client, context.CancelFunc) {
+// CreateIngressReady takes optional IngressOptions. WithCAPool lets parallel
+// tests minting their own TLS secrets (via CreateTLSSecret(t, clients, hosts,
+// pool)) avoid sharing trust state with one another; WithAnnotations carries
+// annotations beyond routing (e.g. an mTLS trust bundle or external-auth
+// hook) onto the underlying Ingress. Callers that pass neither get the
+// historical behavior of trusting defaultCAPool and setting only the
+// ingress-class annotation.
+func CreateIngressReady(t *testing.T, clients *test.Clients, spec v1alpha1.IngressSpec, opts ...IngressOption) (*v1alpha1.Ingress, *http.Client, context.CancelFunc) {
 	t.Helper()
 
 	// Create a client with a dialer based on the Ingress' public load balancer.
-	ing, dialer, cancel := CreateIngressReadyDialContext(t, clients, spec)
+	ing, dialer, cancel := CreateIngressReadyDialContext(t, clients, spec, opts...)
 
-	// TODO(mattmoor): How to get ing?
 	var tlsConfig *tls.Config
 	if len(ing.Spec.TLS) > 0 {
 		// CAs are added to this as TLS secrets are created.
-		tlsConfig = &tls.Config{
-			RootCAs: rootCAs,
-		}
+		tlsConfig = caPoolFor(resolveIngressOptions(opts).pool).TLSConfig()
 	}
 
 	return ing, &http.Client{
@@ -571,7 +621,11 @@ func UpdateIngressReady(t *testing.T, clients *test.Clients, name string, spec v
 }
 
 // This is based on https://golang.org/src/crypto/tls/generate_cert.go
-func CreateTLSSecret(t *testing.T, clients *test.Clients, hosts []string) (string, context.CancelFunc) {
+//
+// CreateTLSSecret takes an optional *CAPool to add the minted cert to
+// (see CreateIngressReady); callers that don't pass one get it added to
+// defaultCAPool, same as before CAPool existed.
+func CreateTLSSecret(t *testing.T, clients *test.Clients, hosts []string, pool ...*CAPool) (string, context.CancelFunc) {
 	t.Helper()
 
 	priv, err := ecdsa.GenerateKey(elliptic.P256(), cryptorand.Reader)
@@ -612,9 +666,7 @@ func CreateTLSSecret(t *testing.T, clients *test.Clients, hosts []string) (strin
 	if err != nil {
 		t.Fatalf("ParseCertificate() = %v", err)
 	}
-	// Ideally we'd undo this in "cancel", but there doesn't
-	// seem to be a mechanism to remove things from a pool.
-	rootCAs.AddCert(cert)
+	removeCA := caPoolFor(pool...).Add(cert)
 
 	certPEM := &bytes.Buffer{}
 	if err := pem.Encode(certPEM, &pem.Block{Type: "CERTIFICATE", Bytes: derBytes}); err != nil {
@@ -656,6 +708,7 @@ func CreateTLSSecret(t *testing.T, clients *test.Clients, hosts []string) (strin
 		if err != nil {
 			t.Errorf("Error cleaning up Secret %s: %v", secret.Name, err)
 		}
+		removeCA()
 	}
 }
 
@@ -738,6 +791,17 @@ func RuntimeRequest(t *testing.T, client *http.Client, url string, opts ...Reque
 		return nil
 	}
 
+	return ParseRuntimeInfo(t, resp)
+}
+
+// ParseRuntimeInfo reads and parses resp's body as the runtime image's JSON
+// payload. It's RuntimeRequest's parsing half, factored out for callers that
+// already hold a *http.Response from a request they built themselves (e.g.
+// one carrying headers RuntimeRequest has no RequestOption for) and would
+// otherwise have to re-issue the request just to read it.
+func ParseRuntimeInfo(t *testing.T, resp *http.Response) *types.RuntimeInfo {
+	t.Helper()
+
 	b, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
 		t.Errorf("Unable to read response body: %v", err)